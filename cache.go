@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// cacheFile is where the known-repos cache lives between runs.
+const cacheFile = "goreleaser_repos.json"
+
+// CacheEntry is what we persist for a repo between runs, so a re-run can
+// skip the REST calls in newRepo and just ask GraphQL whether anything
+// changed.
+type CacheEntry struct {
+	Name          string    `json:"name"`
+	Stars         int       `json:"stars"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastRefreshed time.Time `json:"last_refreshed"`
+	HeadSHA       string    `json:"head_sha"`
+	// ETag is the last Repositories.Get response's ETag, sent back as
+	// If-None-Match so an unchanged repo costs a 304 instead of a full
+	// REST round-trip. Only populated by sources that implement
+	// ConditionalSource.
+	ETag string `json:"etag,omitempty"`
+}
+
+// loadCache reads the cache file, returning an empty map if it doesn't
+// exist yet.
+func loadCache(path string) (map[string]CacheEntry, error) {
+	cache := map[string]CacheEntry{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// saveCache writes the cache file back out.
+func saveCache(path string, cache map[string]CacheEntry) error {
+	b, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// repoGraphQLID is the subset of fields we need per repo for the batched
+// GraphQL query below.
+type repoGraphQLID struct {
+	Canonical string
+	Owner     string
+	Name      string
+}
+
+// githubCanonicalPrefix is how GitHub repos are keyed in the cache, since
+// newSources canonicalizes hits as "host/owner/name".
+const githubCanonicalPrefix = "github.com/"
+
+// githubGraphQLEndpoint is GitHub's GraphQL v4 API.
+const githubGraphQLEndpoint = "https://api.github.com/graphql"
+
+// graphqlBatch is a page's worth (<=100) of repos queried in a single
+// GraphQL request, one aliased `repository(...)` selection per repo so the
+// whole batch round-trips in one HTTP call instead of one per repo.
+type graphqlAlias struct {
+	Canonical string
+	Alias     string
+}
+
+// refreshedRepo is what the batched GraphQL query learns about an
+// already-known GitHub repo: its current stargazer count and
+// default-branch HEAD SHA. describeOne compares HeadSHA against the
+// cached one to decide whether a REST round-trip can be skipped
+// entirely, and uses Stars to keep the cached star count current even
+// when it does skip.
+type refreshedRepo struct {
+	HeadSHA string
+	Stars   int
+}
+
+// refreshKnownRepos batch-queries stargazer counts and default-branch HEAD
+// SHAs for already-known GitHub repos via the GraphQL v4 API, up to 100
+// repos per request, so we don't pay a REST round-trip per repo on every
+// run. It's a no-op for repos from other sources, which don't speak
+// GitHub's GraphQL API. Repos whose HEAD hasn't moved since LastRefreshed
+// are left untouched by the caller; the rest are re-described over REST.
+func refreshKnownRepos(ctx context.Context, cache map[string]CacheEntry) (map[string]refreshedRepo, error) {
+	var ids []repoGraphQLID
+	for canonical := range cache {
+		if !strings.HasPrefix(canonical, githubCanonicalPrefix) {
+			continue
+		}
+		owner, repo, ok := splitRepoName(strings.TrimPrefix(canonical, githubCanonicalPrefix))
+		if !ok {
+			continue
+		}
+		ids = append(ids, repoGraphQLID{Canonical: canonical, Owner: owner, Name: repo})
+	}
+
+	refreshed := map[string]refreshedRepo{}
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		result, err := queryRepoBatch(ctx, batch)
+		if err != nil {
+			log.WithError(err).Warn("graphql batch query failed, will fall back to REST for this batch")
+			continue
+		}
+		for canonical, repo := range result {
+			refreshed[canonical] = refreshedRepo{
+				HeadSHA: repo.DefaultBranchRef.Target.Oid,
+				Stars:   repo.StargazerCount,
+			}
+		}
+	}
+	return refreshed, nil
+}
+
+// graphqlRepoResult is the shape of a single aliased `repository(...)`
+// selection in the batch query's response.
+type graphqlRepoResult struct {
+	StargazerCount   int `json:"stargazerCount"`
+	DefaultBranchRef struct {
+		Target struct {
+			Oid string `json:"oid"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+// queryRepoBatch asks GitHub's GraphQL API for stargazer count and
+// default-branch HEAD for every repo in batch (at most 100), as a single
+// request: one `repoN: repository(owner: ..., name: ...) { ... }` alias
+// per repo, keyed back to the repo's canonical name in the result.
+func queryRepoBatch(ctx context.Context, batch []repoGraphQLID) (map[string]graphqlRepoResult, error) {
+	var fields strings.Builder
+	aliases := make([]graphqlAlias, len(batch))
+	for i, r := range batch {
+		alias := fmt.Sprintf("repo%d", i)
+		aliases[i] = graphqlAlias{Canonical: r.Canonical, Alias: alias}
+		fmt.Fprintf(&fields, "%s: repository(owner: %q, name: %q) { stargazerCount defaultBranchRef { target { oid } } }\n", alias, r.Owner, r.Name)
+	}
+	query := fmt.Sprintf("query { %s }", fields.String())
+
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, githubGraphQLEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+os.Getenv("GITHUB_TOKEN"))
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("graphql: unexpected status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Data   map[string]*graphqlRepoResult `json:"data"`
+		Errors []struct {
+			Message string   `json:"message"`
+			Path    []string `json:"path"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	// A single renamed/deleted/private repo in the batch errors out as a
+	// null data value at that alias, not a request-level failure — keep
+	// whatever aliases did resolve and only let the errored ones fall back
+	// to REST individually, instead of discarding the whole batch.
+	if len(parsed.Errors) > 0 {
+		log.Warnf("graphql batch query returned %d error(s), e.g. %q; affected repos will fall back to REST", len(parsed.Errors), parsed.Errors[0].Message)
+	}
+
+	result := make(map[string]graphqlRepoResult, len(aliases))
+	for _, a := range aliases {
+		if repo := parsed.Data[a.Alias]; repo != nil {
+			result[a.Canonical] = *repo
+		}
+	}
+	return result, nil
+}
+
+// splitRepoName splits a "owner/name" full name into its two parts.
+func splitRepoName(fullName string) (owner, name string, ok bool) {
+	for i := 0; i < len(fullName); i++ {
+		if fullName[i] == '/' {
+			return fullName[:i], fullName[i+1:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	chart "github.com/wcharczuk/go-chart"
+)
+
+var formats = flag.String("format", "svg", "comma-separated list of output formats to render (svg,png,json,csv,prom)")
+
+// ExportRepo is the full per-repo record written out by the json/csv
+// renderers. It carries the extra fields Repo doesn't, since those only
+// come from a fuller REST describe and most callers of Repo don't need
+// them.
+type ExportRepo struct {
+	Name             string    `json:"name"`
+	Stars            int       `json:"stars"`
+	Forks            int       `json:"forks"`
+	Language         string    `json:"language"`
+	License          string    `json:"license"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastCommit       time.Time `json:"last_commit"`
+	GoreleaserConfig string    `json:"goreleaser_config_path"`
+}
+
+// renderer writes repos out in one output format and returns the filename
+// it wrote.
+type renderer func(repos []Repo, extra []ExportRepo) (string, error)
+
+var renderers = map[string]renderer{
+	"svg":  renderSVG,
+	"png":  renderPNG,
+	"json": renderJSON,
+	"csv":  renderCSV,
+	"prom": renderProm,
+}
+
+// renderAll runs every requested --format renderer over the given repos,
+// returning the filenames written.
+func renderAll(repos []Repo, extra []ExportRepo, names []string) ([]string, error) {
+	var written []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		render, ok := renderers[name]
+		if !ok {
+			return written, fmt.Errorf("unknown --format %q", name)
+		}
+		filename, err := render(repos, extra)
+		if err != nil {
+			return written, fmt.Errorf("render %s: %w", name, err)
+		}
+		written = append(written, filename)
+	}
+	return written, nil
+}
+
+func renderSVG(repos []Repo, extra []ExportRepo) (string, error) {
+	return graphRepos(repos, chart.SVG, "svg")
+}
+
+func renderPNG(repos []Repo, extra []ExportRepo) (string, error) {
+	return graphRepos(repos, chart.PNG, "png")
+}
+
+func renderJSON(repos []Repo, extra []ExportRepo) (string, error) {
+	b, err := json.MarshalIndent(extra, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	filename := "repos.json"
+	return filename, ioutil.WriteFile(filename, b, 0644)
+}
+
+func renderCSV(repos []Repo, extra []ExportRepo) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"name", "stars", "forks", "language", "license", "first_seen", "last_commit", "goreleaser_config_path"}
+	if err := w.Write(header); err != nil {
+		return "", err
+	}
+	for _, r := range extra {
+		row := []string{
+			r.Name,
+			strconv.Itoa(r.Stars),
+			strconv.Itoa(r.Forks),
+			r.Language,
+			r.License,
+			r.FirstSeen.Format(time.RFC3339),
+			r.LastCommit.Format(time.RFC3339),
+			r.GoreleaserConfig,
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	filename := "repos.csv"
+	return filename, ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// renderProm writes a node_exporter textfile-collector-compatible .prom
+// file, so goreleaser adoption can be scraped over time.
+func renderProm(repos []Repo, extra []ExportRepo) (string, error) {
+	var buf bytes.Buffer
+	for _, r := range extra {
+		fmt.Fprintf(&buf, "goreleaser_repo_stars{repo=%q} %d\n", r.Name, r.Stars)
+		fmt.Fprintf(&buf, "goreleaser_repo_first_seen_timestamp{repo=%q} %d\n", r.Name, r.FirstSeen.Unix())
+	}
+	filename := "goreleaser_adoption.prom"
+	return filename, ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// graphRepos renders the cumulative-adoption time series in the given
+// chart format, writing it to a filename with the matching extension.
+func graphRepos(repos []Repo, format chart.RendererProvider, ext string) (string, error) {
+	var filename = fmt.Sprintf("chart_%v.%s", time.Now().Format(time.RFC822), ext)
+	var series = chart.TimeSeries{Style: chart.StyleShow()}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Date.Before(repos[j].Date)
+	})
+	for i, repo := range repos {
+		series.XValues = append(series.XValues, repo.Date)
+		series.YValues = append(series.YValues, float64(i))
+	}
+	var graph = chart.Chart{
+		XAxis: chart.XAxis{
+			Name:      "Time",
+			NameStyle: chart.StyleShow(),
+			Style:     chart.StyleShow(),
+		},
+		YAxis: chart.YAxis{
+			Name:      "Using",
+			NameStyle: chart.StyleShow(),
+			Style:     chart.StyleShow(),
+		},
+		Series: []chart.Series{series},
+	}
+	var buffer = bytes.NewBuffer([]byte{})
+	if err := graph.Render(format, buffer); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// graphStarsOverTime renders a dual-Y-axis chart overlaying cumulative
+// repo count against cumulative stars, so adoption and popularity can be
+// compared at a glance.
+func graphStarsOverTime(repos []Repo) (string, error) {
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Date.Before(repos[j].Date)
+	})
+	var countSeries = chart.TimeSeries{
+		Name:  "Repos using goreleaser",
+		Style: chart.StyleShow(),
+	}
+	var starsSeries = chart.TimeSeries{
+		Name:  "Cumulative stars",
+		Style: chart.StyleShow(),
+		YAxis: chart.YAxisSecondary,
+	}
+	var cumulativeStars = 0
+	for i, repo := range repos {
+		cumulativeStars += repo.Stars
+		countSeries.XValues = append(countSeries.XValues, repo.Date)
+		countSeries.YValues = append(countSeries.YValues, float64(i))
+		starsSeries.XValues = append(starsSeries.XValues, repo.Date)
+		starsSeries.YValues = append(starsSeries.YValues, float64(cumulativeStars))
+	}
+
+	var graph = chart.Chart{
+		XAxis: chart.XAxis{
+			Name:      "Time",
+			NameStyle: chart.StyleShow(),
+			Style:     chart.StyleShow(),
+		},
+		YAxis: chart.YAxis{
+			Name:      "Using",
+			NameStyle: chart.StyleShow(),
+			Style:     chart.StyleShow(),
+		},
+		YAxisSecondary: chart.YAxis{
+			Name:      "Stars",
+			NameStyle: chart.StyleShow(),
+			Style:     chart.StyleShow(),
+		},
+		Series: []chart.Series{countSeries, starsSeries},
+	}
+	filename := fmt.Sprintf("chart_stars_%v.svg", time.Now().Format(time.RFC822))
+	var buffer = bytes.NewBuffer([]byte{})
+	if err := graph.Render(chart.SVG, buffer); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// graphTopStars renders a bar chart of the top-N most-starred repos.
+func graphTopStars(repos []Repo, n int) (string, error) {
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Stars > repos[j].Stars
+	})
+	if n > len(repos) {
+		n = len(repos)
+	}
+	var bars []chart.Value
+	for _, repo := range repos[:n] {
+		bars = append(bars, chart.Value{Label: repo.Name, Value: float64(repo.Stars)})
+	}
+	graph := chart.BarChart{
+		Title:    fmt.Sprintf("Top %d repos by stars", n),
+		Height:   512,
+		BarWidth: 30,
+		XAxis:    chart.StyleShow(),
+		YAxis:    chart.YAxis{Style: chart.StyleShow()},
+		Bars:     bars,
+	}
+	filename := fmt.Sprintf("chart_top_stars_%v.svg", time.Now().Format(time.RFC822))
+	var buffer = bytes.NewBuffer([]byte{})
+	if err := graph.Render(chart.SVG, buffer); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"fmt"
-	"io/ioutil"
+	"flag"
 	"os"
 	"sort"
 	"strings"
@@ -12,71 +10,70 @@ import (
 
 	"github.com/apex/log"
 	"github.com/apex/log/handlers/cli"
-	"github.com/google/go-github/github"
-	chart "github.com/wcharczuk/go-chart"
-	"golang.org/x/oauth2"
 )
 
 type Repo struct {
 	Name  string
 	Stars int
 	Date  time.Time
+
+	// FirstSeen is when the cache first recorded this repo, i.e. the
+	// first run that found it — not to be confused with Date, which is
+	// the commit date of the matched goreleaser config file.
+	FirstSeen time.Time
+
+	// Host is the canonical host the repo was found on (e.g.
+	// "github.com"), carried over from the RepoHit that produced it so
+	// host-specific passes like the stale report know which repos they
+	// can act on.
+	Host string
+
+	// Forks, Language, License, and GoreleaserConfigPath are only
+	// populated by sources that can cheaply fetch them (currently just
+	// GitHub); they feed the --format=json/csv export and are otherwise
+	// unused.
+	Forks                int
+	Language             string
+	License              string
+	GoreleaserConfigPath string
 }
 
 func init() {
 	log.SetHandler(cli.New(os.Stdout))
 }
 
+var sourceNames = flag.String("source", "github", "comma-separated list of sources to search (github,gitlab,gitea,sourcehut)")
+
 func main() {
+	flag.Parse()
 	log.Info("starting up...")
 	var ctx = context.Background()
-	var ts = oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
-	)
-	var client = github.NewClient(oauth2.NewClient(ctx, ts))
 	var repos []Repo
 
-	for _, file := range []string{"goreleaser.yml", "goreleaser.yaml"} {
-		log.Infof("looking for repos with a %s file...", file)
-		var opts = &github.SearchOptions{
-			ListOptions: github.ListOptions{
-				Page:    1,
-				PerPage: 100,
-			},
-		}
-		for {
-			result, resp, err := client.Search.Code(
-				ctx,
-				fmt.Sprintf("filename:%s language:yaml", file),
-				opts,
-			)
-			if _, ok := err.(*github.RateLimitError); ok {
-				log.Warn("hit rate limit")
-				time.Sleep(10 * time.Second)
-				continue
-			}
+	sources, err := newSources(ctx, strings.Split(*sourceNames, ","))
+	if err != nil {
+		log.WithError(err).Fatal("failed to build sources")
+	}
+
+	cache, err := loadCache(cacheFile)
+	if err != nil {
+		log.WithError(err).Fatal("failed to load cache")
+	}
+	log.Infof("loaded %d repos from cache", len(cache))
+	refreshed, err := refreshKnownRepos(ctx, cache)
+	if err != nil {
+		log.WithError(err).Fatal("failed to refresh known repos via graphql")
+	}
+
+	var seen = map[string]bool{}
+	for _, source := range sources {
+		for _, file := range []string{"goreleaser.yml", "goreleaser.yaml"} {
+			log.Infof("looking for repos with a %s file...", file)
+			hits, err := source.Search(ctx, file)
 			if err != nil {
 				log.WithError(err).Fatal("failed to gather results")
 			}
-			log.Infof("found %d results", len(result.CodeResults))
-			for _, result := range result.CodeResults {
-				if exists(result.Repository.GetFullName(), repos) {
-					continue
-				}
-				repo, err := newRepo(ctx, client, result)
-				if err != nil {
-					log.WithField("repo", result.Repository.GetFullName()).
-						WithError(err).Error("failed to get repo details")
-				}
-				if repo.Name == "" {
-					continue
-				}
-				repos = append(repos, repo)
-			}
-			if resp.NextPage == 0 {
-				break
-			}
-			opts.Page = resp.NextPage
+			repos = append(repos, describeHits(ctx, source, hits, cache, refreshed, seen)...)
 		}
 	}
 	sort.Slice(repos, func(i, j int) bool {
@@ -89,107 +86,59 @@ func main() {
 	for _, repo := range repos {
 		log.Infof("%s with %d stars (using since %v)", repo.Name, repo.Stars, repo.Date)
 	}
-	graph, err := graphRepos(repos)
+	extra := toExportRepos(repos)
+	written, err := renderAll(repos, extra, strings.Split(*formats, ","))
 	if err != nil {
-		log.WithError(err).Fatal("failed to graph repos")
+		log.WithError(err).Fatal("failed to render repos")
 	}
-	log.Infof("\ngraph saved at %s", graph)
-}
+	log.Infof("\nwrote %s", strings.Join(written, ", "))
 
-func newRepo(ctx context.Context, client *github.Client, result github.CodeResult) (Repo, error) {
-	repo, _, err := client.Repositories.Get(
-		ctx,
-		result.Repository.Owner.GetLogin(),
-		result.Repository.GetName(),
-	)
-	if _, ok := err.(*github.RateLimitError); ok {
-		log.Warn("hit rate limit")
-		time.Sleep(10 * time.Second)
-		return newRepo(ctx, client, result)
-	}
+	starsGraph, err := graphStarsOverTime(repos)
 	if err != nil {
-		return Repo{}, err
-	}
-	if strings.HasPrefix(result.GetPath(), "/") {
-		return Repo{}, nil
-	}
-	commits, _, err := client.Repositories.ListCommits(
-		ctx,
-		repo.Owner.GetLogin(),
-		repo.GetName(),
-		&github.CommitsListOptions{
-			Path: result.GetPath(),
-		},
-	)
-	if _, ok := err.(*github.RateLimitError); ok {
-		log.Warn("hit rate limit")
-		time.Sleep(10 * time.Second)
-		return newRepo(ctx, client, result)
-	}
-	if err != nil || len(commits) == 0 {
-		return Repo{}, err
-	}
-	commit := commits[len(commits)-1]
-	c, _, err := client.Git.GetCommit(
-		ctx,
-		repo.Owner.GetLogin(),
-		repo.GetName(),
-		commit.GetSHA(),
-	)
-	if _, ok := err.(*github.RateLimitError); ok {
-		log.Warn("hit rate limit")
-		time.Sleep(10 * time.Second)
-		return newRepo(ctx, client, result)
+		log.WithError(err).Error("failed to graph stars over time")
+	} else {
+		log.Infof("stars-over-time graph saved at %s", starsGraph)
 	}
+	topStarsGraph, err := graphTopStars(repos, 25)
 	if err != nil {
-		return Repo{}, err
+		log.WithError(err).Error("failed to graph top repos by stars")
+	} else {
+		log.Infof("top-stars graph saved at %s", topStarsGraph)
 	}
 
-	return Repo{
-		Name:  repo.GetFullName(),
-		Stars: repo.GetStargazersCount(),
-		Date:  c.Committer.GetDate(),
-	}, nil
-}
-
-func exists(name string, rs []Repo) bool {
-	for _, r := range rs {
-		if r.Name == name {
-			return true
+	restClient := newGitHubRESTClient(ctx)
+	report, classified, err := writeStaleReport(ctx, restClient, repos)
+	if err != nil {
+		log.WithError(err).Error("failed to write stale report")
+	} else {
+		log.Infof("stale report saved at %s", report)
+	}
+	if *openIssues && err == nil {
+		if err := openStaleIssues(ctx, restClient, classified); err != nil {
+			log.WithError(err).Error("failed to open stale tracking issues")
 		}
 	}
-	return false
-}
 
-func graphRepos(repos []Repo) (string, error) {
-	var filename = fmt.Sprintf("chart_%v.svg", time.Now().Format(time.RFC822))
-	var series = chart.TimeSeries{Style: chart.StyleShow()}
-	sort.Slice(repos, func(i, j int) bool {
-		return repos[i].Date.Before(repos[j].Date)
-	})
-	for i, repo := range repos {
-		series.XValues = append(series.XValues, repo.Date)
-		series.YValues = append(series.YValues, float64(i))
-	}
-	var graph = chart.Chart{
-		XAxis: chart.XAxis{
-			Name:      "Time",
-			NameStyle: chart.StyleShow(),
-			Style:     chart.StyleShow(),
-		},
-		YAxis: chart.YAxis{
-			Name:      "Using",
-			NameStyle: chart.StyleShow(),
-			Style:     chart.StyleShow(),
-		},
-		Series: []chart.Series{series},
+	if err := saveCache(cacheFile, cache); err != nil {
+		log.WithError(err).Fatal("failed to save cache")
 	}
-	var buffer = bytes.NewBuffer([]byte{})
-	if err := graph.Render(chart.SVG, buffer); err != nil {
-		return "", err
-	}
-	if err := ioutil.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
-		return "", err
+}
+
+// toExportRepos converts the in-memory Repo slice into the richer
+// ExportRepo shape used by the json/csv/prom renderers.
+func toExportRepos(repos []Repo) []ExportRepo {
+	extra := make([]ExportRepo, len(repos))
+	for i, r := range repos {
+		extra[i] = ExportRepo{
+			Name:             r.Name,
+			Stars:            r.Stars,
+			Forks:            r.Forks,
+			Language:         r.Language,
+			License:          r.License,
+			FirstSeen:        r.FirstSeen,
+			LastCommit:       r.Date,
+			GoreleaserConfig: r.GoreleaserConfigPath,
+		}
 	}
-	return filename, nil
+	return extra
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"golang.org/x/sync/errgroup"
+)
+
+var workerCount = flag.Int("workers", 8, "number of concurrent workers describing repos found by a source")
+
+// describeHits runs source.Describe (or, for sources that support it,
+// DescribeIfChanged) over every hit using a pool of --workers goroutines,
+// instead of the strictly sequential describe-one-repo-at-a-time the tool
+// used to do. cache/refreshed/seen are shared across every source and
+// guarded by a mutex since workers from different sources can run at once.
+func describeHits(ctx context.Context, source Source, hits []RepoHit, cache map[string]CacheEntry, refreshed map[string]refreshedRepo, seen map[string]bool) []Repo {
+	var (
+		mu    sync.Mutex
+		repos []Repo
+	)
+
+	workers := *workerCount
+	if workers < 1 {
+		workers = 1
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, workers)
+
+	for _, hit := range hits {
+		hit := hit
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			repo, ok := describeOne(gctx, source, hit, &mu, cache, refreshed, seen)
+			if !ok {
+				return nil
+			}
+			mu.Lock()
+			repos = append(repos, repo)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		log.WithError(err).Error("worker pool stopped early")
+	}
+	return repos
+}
+
+// describeOne handles a single hit: skip it if it's already been seen this
+// run (by any source), reuse the cache entry when possible (HEAD
+// unchanged, or a conditional describe reports nothing changed), and
+// otherwise fetch and cache a fresh Repo.
+func describeOne(ctx context.Context, source Source, hit RepoHit, mu *sync.Mutex, cache map[string]CacheEntry, refreshed map[string]refreshedRepo, seen map[string]bool) (Repo, bool) {
+	canonical := hit.CanonicalName()
+
+	mu.Lock()
+	if seen[canonical] {
+		mu.Unlock()
+		return Repo{}, false
+	}
+	seen[canonical] = true
+	entry, known := cache[canonical]
+	info := refreshed[canonical]
+	mu.Unlock()
+	if known && info.HeadSHA != "" && info.HeadSHA == entry.HeadSHA {
+		touchCache(mu, cache, canonical, entry, info.Stars, entry.HeadSHA, entry.ETag)
+		return Repo{Name: entry.Name, Stars: info.Stars, Date: entry.FirstSeen, FirstSeen: entry.FirstSeen, Host: hit.Host}, true
+	}
+
+	var repo Repo
+	var etag string
+	var err error
+	if cs, ok := source.(ConditionalSource); ok {
+		var unchanged bool
+		repo, etag, unchanged, err = cs.DescribeIfChanged(ctx, hit, entry.ETag)
+		if err == nil && unchanged {
+			// A 304 tells us the repo's metadata hasn't changed, but not
+			// its HEAD SHA directly; use this run's GraphQL-fetched SHA so
+			// the fast path above can fire on a later run instead of
+			// paying for a conditional GET forever.
+			headSHA := entry.HeadSHA
+			if info.HeadSHA != "" {
+				headSHA = info.HeadSHA
+			}
+			stars := entry.Stars
+			if info.HeadSHA != "" {
+				stars = info.Stars
+			}
+			touchCache(mu, cache, canonical, entry, stars, headSHA, etag)
+			return Repo{Name: entry.Name, Stars: stars, Date: entry.FirstSeen, FirstSeen: entry.FirstSeen, Host: hit.Host}, true
+		}
+	} else {
+		repo, err = source.Describe(ctx, hit)
+	}
+	if err != nil {
+		log.WithField("repo", canonical).WithError(err).Error("failed to get repo details")
+		if etag != "" && etag != entry.ETag {
+			mu.Lock()
+			e := cache[canonical]
+			e.ETag = etag
+			cache[canonical] = e
+			mu.Unlock()
+		}
+		return Repo{}, false
+	}
+	if repo.Name == "" {
+		return Repo{}, false
+	}
+
+	mu.Lock()
+	firstSeen := time.Now()
+	if e, ok := cache[canonical]; ok {
+		firstSeen = e.FirstSeen
+	}
+	cache[canonical] = CacheEntry{
+		Name:          repo.Name,
+		Stars:         repo.Stars,
+		FirstSeen:     firstSeen,
+		LastRefreshed: time.Now(),
+		HeadSHA:       info.HeadSHA,
+		ETag:          etag,
+	}
+	mu.Unlock()
+	repo.FirstSeen = firstSeen
+	return repo, true
+}
+
+// touchCache refreshes a cache entry's Stars/HeadSHA/ETag/LastRefreshed in
+// place without re-describing the repo, for the fast paths in describeOne
+// that learn these from GraphQL or a conditional-GET 304 instead of a full
+// describe.
+func touchCache(mu *sync.Mutex, cache map[string]CacheEntry, canonical string, entry CacheEntry, stars int, headSHA, etag string) {
+	mu.Lock()
+	defer mu.Unlock()
+	entry.Stars = stars
+	entry.HeadSHA = headSHA
+	entry.ETag = etag
+	entry.LastRefreshed = time.Now()
+	cache[canonical] = entry
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// RepoHit is a single filename match returned by a Source's Search, before
+// it has been turned into a full Repo via Describe.
+type RepoHit struct {
+	// Host is the canonical host the hit came from, e.g. "github.com" or
+	// "gitlab.com". Used together with Owner/Name to dedupe repos found
+	// through more than one Source.
+	Host  string
+	Owner string
+	Name  string
+	// Path is the path of the matched file within the repo, relative to
+	// its root.
+	Path string
+}
+
+// CanonicalName returns the "host/owner/name" identifier used to merge
+// hits across sources.
+func (h RepoHit) CanonicalName() string {
+	return fmt.Sprintf("%s/%s/%s", h.Host, h.Owner, h.Name)
+}
+
+// Source is a code-search backend that can find repos containing a given
+// filename and describe them as a Repo. Each supported git host gets its
+// own implementation.
+type Source interface {
+	// Search returns every repo hit containing the given filename.
+	Search(ctx context.Context, filename string) ([]RepoHit, error)
+	// Describe turns a hit into a fully populated Repo, fetching stars
+	// and the commit date of the matched file.
+	Describe(ctx context.Context, hit RepoHit) (Repo, error)
+}
+
+// ConditionalSource is implemented by sources that can skip re-describing
+// a repo entirely when nothing has changed since the last run, using a
+// conditional request (e.g. HTTP's If-None-Match/ETag). describeHits
+// prefers this over Describe when a source supports it.
+type ConditionalSource interface {
+	Source
+	// DescribeIfChanged behaves like Describe, but given the ETag from a
+	// prior run it returns unchanged=true (and a zero Repo) instead of
+	// doing the full describe when the server reports nothing changed.
+	// It always returns the ETag to persist for next time.
+	DescribeIfChanged(ctx context.Context, hit RepoHit, etag string) (repo Repo, newETag string, unchanged bool, err error)
+}
+
+// newSources builds the requested Sources from their names, reading each
+// one's token from the environment (e.g. "github" reads GITHUB_TOKEN,
+// "gitlab" reads GITLAB_TOKEN).
+func newSources(ctx context.Context, names []string) ([]Source, error) {
+	var sources []Source
+	for _, name := range names {
+		source, err := newSource(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func newSource(ctx context.Context, name string) (Source, error) {
+	switch name {
+	case "github":
+		return newGitHubSource(ctx), nil
+	case "gitlab":
+		return newGitLabSource(ctx), nil
+	case "gitea":
+		return newGiteaSource(ctx), nil
+	case "sourcehut":
+		return newSourceHutSource(ctx), nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", name)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// GiteaSource searches a Gitea instance (GITEA_URL, default
+// https://gitea.com) using its repo search endpoint. Gitea has no
+// content/code search API, so we search by repo name/topic and then
+// confirm the file exists via the contents API.
+type GiteaSource struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newGiteaSource(ctx context.Context) *GiteaSource {
+	baseURL := os.Getenv("GITEA_URL")
+	if baseURL == "" {
+		baseURL = "https://gitea.com"
+	}
+	return &GiteaSource{
+		baseURL: baseURL,
+		token:   os.Getenv("GITEA_TOKEN"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type giteaSearchResponse struct {
+	OK   bool `json:"ok"`
+	Data []struct {
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+func (s *GiteaSource) Search(ctx context.Context, filename string) ([]RepoHit, error) {
+	var hits []RepoHit
+	for page := 1; ; page++ {
+		var result giteaSearchResponse
+		url := fmt.Sprintf("%s/api/v1/repos/search?q=goreleaser&limit=50&page=%d", s.baseURL, page)
+		if err := s.get(ctx, url, &result); err != nil {
+			return nil, err
+		}
+		if len(result.Data) == 0 {
+			break
+		}
+		for _, repo := range result.Data {
+			if !s.hasFile(ctx, repo.Owner.Login, repo.Name, filename) {
+				continue
+			}
+			hits = append(hits, RepoHit{
+				Host:  "gitea.com",
+				Owner: repo.Owner.Login,
+				Name:  repo.Name,
+				Path:  filename,
+			})
+		}
+	}
+	return hits, nil
+}
+
+func (s *GiteaSource) hasFile(ctx context.Context, owner, name, filename string) bool {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s/contents/%s", s.baseURL, owner, name, filename)
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return false
+	}
+	s.auth(req)
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (s *GiteaSource) Describe(ctx context.Context, hit RepoHit) (Repo, error) {
+	var repo struct {
+		FullName string `json:"full_name"`
+		Stars    int    `json:"stars_count"`
+	}
+	url := fmt.Sprintf("%s/api/v1/repos/%s/%s", s.baseURL, hit.Owner, hit.Name)
+	if err := s.get(ctx, url, &repo); err != nil {
+		return Repo{}, err
+	}
+
+	var commits []struct {
+		Created time.Time `json:"created"`
+	}
+	commitsURL := fmt.Sprintf("%s/api/v1/repos/%s/%s/commits?path=%s&limit=1", s.baseURL, hit.Owner, hit.Name, hit.Path)
+	if err := s.get(ctx, commitsURL, &commits); err != nil || len(commits) == 0 {
+		return Repo{}, err
+	}
+
+	return Repo{
+		Name:  repo.FullName,
+		Stars: repo.Stars,
+		Date:  commits[len(commits)-1].Created,
+		Host:  hit.Host,
+	}, nil
+}
+
+func (s *GiteaSource) auth(req *http.Request) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "token "+s.token)
+	}
+}
+
+func (s *GiteaSource) get(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	s.auth(req)
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		log.Warn("hit rate limit")
+		time.Sleep(10 * time.Second)
+		return s.get(ctx, url, out)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gitea: unexpected status %d for %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-github/github"
+	"golang.org/x/oauth2"
+)
+
+// GitHubSource searches github.com via the code search API and describes
+// repos via the REST API. Rate-limit backoff for every call made through
+// it is centralized in doGH, so the many workers describeHits spins up all
+// pause together instead of retrying independently.
+type GitHubSource struct {
+	client *github.Client
+	limits *rateLimiter
+}
+
+func newGitHubSource(ctx context.Context) *GitHubSource {
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
+	)
+	return &GitHubSource{
+		client: github.NewClient(oauth2.NewClient(ctx, ts)),
+		limits: newRateLimiter(),
+	}
+}
+
+// rateLimiter centralizes GitHub rate-limit backoff across every caller:
+// when one goroutine hits a limit, every other goroutine sharing this
+// limiter waits on the same sync.Cond until the shared reset time has
+// passed, instead of each sleeping and retrying blindly.
+type rateLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	pausedTil time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	rl := &rateLimiter{}
+	rl.cond = sync.NewCond(&rl.mu)
+	return rl
+}
+
+func (rl *rateLimiter) wait() {
+	rl.mu.Lock()
+	for time.Now().Before(rl.pausedTil) {
+		rl.cond.Wait()
+	}
+	rl.mu.Unlock()
+}
+
+func (rl *rateLimiter) pauseUntil(t time.Time) {
+	rl.mu.Lock()
+	if t.After(rl.pausedTil) {
+		rl.pausedTil = t
+	}
+	rl.mu.Unlock()
+	time.AfterFunc(time.Until(t), rl.cond.Broadcast)
+}
+
+// doGH runs fn, centralizing GitHub's two rate-limit shapes into one
+// shared pause: the primary limit (an *github.RateLimitError, whose
+// Rate.Reset tells us exactly when to resume) and the secondary/abuse
+// limit (an *github.AbuseRateLimitError, which carries its own
+// Retry-After). Once the pause elapses every waiting caller retries fn.
+func doGH(rl *rateLimiter, fn func() (*github.Response, error)) (*github.Response, error) {
+	for {
+		rl.wait()
+		resp, err := fn()
+		if rateErr, ok := err.(*github.RateLimitError); ok {
+			log.Warnf("hit rate limit, resets at %v", rateErr.Rate.Reset.Time)
+			rl.pauseUntil(rateErr.Rate.Reset.Time)
+			continue
+		}
+		if abuseErr, ok := err.(*github.AbuseRateLimitError); ok {
+			wait := 10 * time.Second
+			if abuseErr.RetryAfter != nil {
+				wait = *abuseErr.RetryAfter
+			}
+			log.Warnf("hit secondary rate limit, retrying in %v", wait)
+			rl.pauseUntil(time.Now().Add(wait))
+			continue
+		}
+		return resp, err
+	}
+}
+
+func (s *GitHubSource) Search(ctx context.Context, filename string) ([]RepoHit, error) {
+	var hits []RepoHit
+	var opts = &github.SearchOptions{
+		ListOptions: github.ListOptions{
+			Page:    1,
+			PerPage: 100,
+		},
+	}
+	for {
+		var result *github.CodeSearchResult
+		resp, err := doGH(s.limits, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			result, resp, err = s.client.Search.Code(
+				ctx,
+				fmt.Sprintf("filename:%s language:yaml", filename),
+				opts,
+			)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("found %d results", len(result.CodeResults))
+		for _, result := range result.CodeResults {
+			hits = append(hits, RepoHit{
+				Host:  "github.com",
+				Owner: result.Repository.Owner.GetLogin(),
+				Name:  result.Repository.GetName(),
+				Path:  result.GetPath(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return hits, nil
+}
+
+func (s *GitHubSource) Describe(ctx context.Context, hit RepoHit) (Repo, error) {
+	repo, _, err := s.describe(ctx, hit, "")
+	return repo, err
+}
+
+// DescribeIfChanged implements ConditionalSource: it sends the previous
+// run's ETag as If-None-Match on the repo-metadata request, and if GitHub
+// answers 304 Not Modified it skips the commit lookups entirely and
+// reports unchanged=true.
+func (s *GitHubSource) DescribeIfChanged(ctx context.Context, hit RepoHit, etag string) (Repo, string, bool, error) {
+	repo, newETag, err := s.describe(ctx, hit, etag)
+	if err != nil {
+		return Repo{}, newETag, false, err
+	}
+	if repo.Name == "" && newETag == etag && etag != "" {
+		return Repo{}, newETag, true, nil
+	}
+	return repo, newETag, false, nil
+}
+
+func (s *GitHubSource) describe(ctx context.Context, hit RepoHit, etag string) (Repo, string, error) {
+	req, err := s.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s", hit.Owner, hit.Name), nil)
+	if err != nil {
+		return Repo{}, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	var ghRepo github.Repository
+	resp, err := doGH(s.limits, func() (*github.Response, error) {
+		return s.client.Do(ctx, req, &ghRepo)
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return Repo{}, etag, nil
+	}
+	if err != nil {
+		return Repo{}, "", err
+	}
+	newETag := resp.Header.Get("ETag")
+
+	if strings.HasPrefix(hit.Path, "/") {
+		return Repo{}, newETag, nil
+	}
+	var commits []*github.RepositoryCommit
+	_, err = doGH(s.limits, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		commits, resp, err = s.client.Repositories.ListCommits(
+			ctx,
+			ghRepo.Owner.GetLogin(),
+			ghRepo.GetName(),
+			&github.CommitsListOptions{Path: hit.Path},
+		)
+		return resp, err
+	})
+	if err != nil || len(commits) == 0 {
+		return Repo{}, newETag, err
+	}
+	commit := commits[len(commits)-1]
+
+	var c *github.Commit
+	_, err = doGH(s.limits, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		c, resp, err = s.client.Git.GetCommit(ctx, ghRepo.Owner.GetLogin(), ghRepo.GetName(), commit.GetSHA())
+		return resp, err
+	})
+	if err != nil {
+		return Repo{}, newETag, err
+	}
+
+	var license string
+	if ghRepo.GetLicense() != nil {
+		license = ghRepo.GetLicense().GetSPDXID()
+	}
+	return Repo{
+		Name:                 ghRepo.GetFullName(),
+		Stars:                ghRepo.GetStargazersCount(),
+		Date:                 c.Committer.GetDate(),
+		Host:                 hit.Host,
+		Forks:                ghRepo.GetForksCount(),
+		Language:             ghRepo.GetLanguage(),
+		License:              license,
+		GoreleaserConfigPath: hit.Path,
+	}, newETag, nil
+}
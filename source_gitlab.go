@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/xanzy/go-gitlab"
+)
+
+// GitLabSource searches gitlab.com (or a self-hosted instance, via
+// GITLAB_URL) using the advanced search API's blob search.
+type GitLabSource struct {
+	client *gitlab.Client
+}
+
+func newGitLabSource(ctx context.Context) *GitLabSource {
+	var opts []gitlab.ClientOptionFunc
+	if url := os.Getenv("GITLAB_URL"); url != "" {
+		opts = append(opts, gitlab.WithBaseURL(url))
+	}
+	client, err := gitlab.NewClient(os.Getenv("GITLAB_TOKEN"), opts...)
+	if err != nil {
+		log.WithError(err).Fatal("failed to build gitlab client")
+	}
+	return &GitLabSource{client: client}
+}
+
+func (s *GitLabSource) Search(ctx context.Context, filename string) ([]RepoHit, error) {
+	var hits []RepoHit
+	var opts = &gitlab.SearchOptions{ListOptions: gitlab.ListOptions{Page: 1, PerPage: 100}}
+	for {
+		blobs, resp, err := s.client.Search.Blobs("filename:"+filename, opts)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("found %d results", len(blobs))
+		for _, blob := range blobs {
+			project, _, err := s.client.Projects.GetProject(blob.ProjectID, nil)
+			if err != nil {
+				log.WithError(err).WithField("project_id", blob.ProjectID).
+					Warn("failed to resolve blob's project")
+				continue
+			}
+			owner, name, ok := splitRepoName(project.PathWithNamespace)
+			if !ok {
+				continue
+			}
+			hits = append(hits, RepoHit{
+				Host:  "gitlab.com",
+				Owner: owner,
+				Name:  name,
+				Path:  blob.Filename,
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return hits, nil
+}
+
+func (s *GitLabSource) Describe(ctx context.Context, hit RepoHit) (Repo, error) {
+	pid := hit.Owner + "/" + hit.Name
+	project, _, err := s.client.Projects.GetProject(pid, nil)
+	if err != nil {
+		return Repo{}, err
+	}
+	commits, _, err := s.client.Commits.ListCommits(pid, &gitlab.ListCommitsOptions{Path: &hit.Path})
+	if err != nil || len(commits) == 0 {
+		return Repo{}, err
+	}
+	commit := commits[len(commits)-1]
+
+	date := commit.CommittedDate
+	if date == nil {
+		now := time.Now()
+		date = &now
+	}
+	return Repo{
+		Name:  project.PathWithNamespace,
+		Stars: project.StarCount,
+		Date:  *date,
+		Host:  hit.Host,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// SourceHutSource searches git.sr.ht repos using its paginated JSON API.
+// SourceHut doesn't do content search, so (like GiteaSource) we list a
+// user's/org's repos and confirm the target file is present via the
+// tree-browsing endpoint.
+type SourceHutSource struct {
+	baseURL string
+	token   string
+	owners  []string
+	http    *http.Client
+}
+
+func newSourceHutSource(ctx context.Context) *SourceHutSource {
+	baseURL := os.Getenv("SOURCEHUT_URL")
+	if baseURL == "" {
+		baseURL = "https://git.sr.ht"
+	}
+	var owners []string
+	if raw := os.Getenv("SOURCEHUT_OWNERS"); raw != "" {
+		owners = strings.Split(raw, ",")
+	}
+	return &SourceHutSource{
+		baseURL: baseURL,
+		token:   os.Getenv("SOURCEHUT_TOKEN"),
+		owners:  owners,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// sourceHutPage is the shape of every paginated SourceHut list endpoint:
+// a page of results plus an opaque cursor for the next one.
+type sourceHutPage struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+func (s *SourceHutSource) Search(ctx context.Context, filename string) ([]RepoHit, error) {
+	var hits []RepoHit
+	for _, owner := range s.owners {
+		cursor := ""
+		for {
+			url := fmt.Sprintf("%s/api/%s/repos", s.baseURL, owner)
+			if cursor != "" {
+				url += "?cursor=" + cursor
+			}
+			var page sourceHutPage
+			if err := s.get(ctx, url, &page); err != nil {
+				return nil, err
+			}
+			log.Infof("found %d results", len(page.Results))
+			for _, repo := range page.Results {
+				if !s.hasFile(ctx, owner, repo.Name, filename) {
+					continue
+				}
+				hits = append(hits, RepoHit{
+					Host:  "sr.ht",
+					Owner: owner,
+					Name:  repo.Name,
+					Path:  filename,
+				})
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+	}
+	return hits, nil
+}
+
+func (s *SourceHutSource) hasFile(ctx context.Context, owner, name, filename string) bool {
+	url := fmt.Sprintf("%s/api/%s/repos/%s/tree/HEAD/item/%s", s.baseURL, owner, name, filename)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	s.auth(req)
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (s *SourceHutSource) Describe(ctx context.Context, hit RepoHit) (Repo, error) {
+	var repo struct {
+		Name    string `json:"name"`
+		Updated string `json:"updated"`
+	}
+	url := fmt.Sprintf("%s/api/%s/repos/%s", s.baseURL, hit.Owner, hit.Name)
+	if err := s.get(ctx, url, &repo); err != nil {
+		return Repo{}, err
+	}
+	date, err := time.Parse(time.RFC3339, repo.Updated)
+	if err != nil {
+		date = time.Now()
+	}
+	return Repo{
+		Name: hit.Owner + "/" + repo.Name,
+		// SourceHut doesn't expose a stargazer count.
+		Stars: 0,
+		Date:  date,
+		Host:  hit.Host,
+	}, nil
+}
+
+func (s *SourceHutSource) auth(req *http.Request) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "token "+s.token)
+	}
+}
+
+func (s *SourceHutSource) get(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	s.auth(req)
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		log.Warn("hit rate limit")
+		time.Sleep(10 * time.Second)
+		return s.get(ctx, url, out)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sourcehut: unexpected status %d for %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
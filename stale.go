@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-github/github"
+)
+
+// RepoStatus classifies a repo's health for the stale report.
+type RepoStatus string
+
+const (
+	StatusActive   RepoStatus = "active"
+	StatusStale    RepoStatus = "stale"
+	StatusArchived RepoStatus = "archived"
+	StatusDead     RepoStatus = "dead"
+)
+
+var staleThreshold = flag.Duration("stale-threshold", 365*24*time.Hour, "how long since the last push before a repo is considered stale")
+var openIssues = flag.Bool("open-issues", false, "open a tracking issue on --meta-repo for each stale/dead repo found")
+var metaRepo = flag.String("meta-repo", "", "owner/name of the repo to file stale-tracking issues against, when --open-issues is set")
+var maxIssuesPerRun = flag.Int("max-issues-per-run", 10, "maximum number of tracking issues to open in a single run")
+
+// classifyRepo decides whether a GitHub repo is active, stale, archived, or
+// dead (its homepage no longer resolves where it used to).
+func classifyRepo(ctx context.Context, repo *github.Repository) RepoStatus {
+	if repo.GetArchived() {
+		return StatusArchived
+	}
+	if time.Since(repo.GetPushedAt().Time) > *staleThreshold {
+		return StatusStale
+	}
+	if homepage := repo.GetHomepage(); homepage != "" && homepageDead(ctx, homepage) {
+		return StatusDead
+	}
+	return StatusActive
+}
+
+// homepageDead HEAD-requests a repo's homepage and reports whether it 404s
+// or redirects to a different host than the one it was set up for.
+func homepageDead(ctx context.Context, homepage string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, homepage, nil)
+	if err != nil {
+		return false
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return true
+	}
+	if resp.Request != nil && resp.Request.URL.Host != "" {
+		origHost := req.URL.Host
+		if resp.Request.URL.Host != origHost {
+			return true
+		}
+	}
+	return false
+}
+
+// staleRepo pairs a Repo with the classification and raw GitHub data it was
+// classified from.
+type staleRepo struct {
+	Repo   Repo
+	Status RepoStatus
+}
+
+// writeStaleReport classifies every repo (GitHub-described repos only,
+// since archived/pushed-at data comes from the REST API) and writes a
+// stale_report.md summarizing the counts and listing the offenders.
+func writeStaleReport(ctx context.Context, client *github.Client, repos []Repo) (string, []staleRepo, error) {
+	var classified []staleRepo
+	var counts = map[RepoStatus]int{}
+	for _, repo := range repos {
+		if repo.Host != "github.com" {
+			continue
+		}
+		owner, name, ok := splitRepoName(repo.Name)
+		if !ok {
+			continue
+		}
+		ghRepo, _, err := client.Repositories.Get(ctx, owner, name)
+		if err != nil {
+			log.WithField("repo", repo.Name).WithError(err).
+				Warn("failed to fetch repo details for stale classification")
+			continue
+		}
+		status := classifyRepo(ctx, ghRepo)
+		counts[status]++
+		classified = append(classified, staleRepo{Repo: repo, Status: status})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Stale report\n\n")
+	fmt.Fprintf(&b, "- active: %d\n", counts[StatusActive])
+	fmt.Fprintf(&b, "- stale: %d\n", counts[StatusStale])
+	fmt.Fprintf(&b, "- archived: %d\n", counts[StatusArchived])
+	fmt.Fprintf(&b, "- dead: %d\n\n", counts[StatusDead])
+	for _, status := range []RepoStatus{StatusStale, StatusArchived, StatusDead} {
+		fmt.Fprintf(&b, "## %s\n\n", status)
+		for _, c := range classified {
+			if c.Status != status {
+				continue
+			}
+			fmt.Fprintf(&b, "- %s (%d stars)\n", c.Repo.Name, c.Repo.Stars)
+		}
+		fmt.Fprintln(&b)
+	}
+
+	filename := "stale_report.md"
+	if err := ioutil.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		return "", nil, err
+	}
+	return filename, classified, nil
+}
+
+// openStaleIssues opens a tracking issue on --meta-repo for each stale or
+// dead repo, up to --max-issues-per-run, skipping repos that already have
+// an open issue with the same title.
+func openStaleIssues(ctx context.Context, client *github.Client, classified []staleRepo) error {
+	if *metaRepo == "" {
+		return fmt.Errorf("--open-issues requires --meta-repo")
+	}
+	owner, name, ok := splitRepoName(*metaRepo)
+	if !ok {
+		return fmt.Errorf("--meta-repo must be owner/name, got %q", *metaRepo)
+	}
+
+	existing, err := existingIssueTitles(ctx, client, owner, name)
+	if err != nil {
+		return err
+	}
+
+	opened := 0
+	for _, c := range classified {
+		if opened >= *maxIssuesPerRun {
+			log.Warnf("hit --max-issues-per-run (%d), skipping the rest", *maxIssuesPerRun)
+			break
+		}
+		if c.Status != StatusStale && c.Status != StatusDead {
+			continue
+		}
+		title := fmt.Sprintf("%s looks %s", c.Repo.Name, c.Status)
+		if existing[title] {
+			continue
+		}
+		_, _, err := client.Issues.Create(ctx, owner, name, &github.IssueRequest{
+			Title: github.String(title),
+			Body:  github.String(fmt.Sprintf("%s has %d stars and was last classified as %s by the goreleaser adoption tracker.", c.Repo.Name, c.Repo.Stars, c.Status)),
+		})
+		if _, ok := err.(*github.RateLimitError); ok {
+			log.Warn("hit rate limit")
+			time.Sleep(10 * time.Second)
+			continue
+		}
+		if err != nil {
+			log.WithField("repo", c.Repo.Name).WithError(err).Error("failed to open tracking issue")
+			continue
+		}
+		opened++
+	}
+	log.Infof("opened %d tracking issues on %s", opened, *metaRepo)
+	return nil
+}
+
+// existingIssueTitles lists the titles of all currently open issues on the
+// meta repo, so we don't file duplicates.
+func existingIssueTitles(ctx context.Context, client *github.Client, owner, name string) (map[string]bool, error) {
+	titles := map[string]bool{}
+	var opts = &github.IssueListByRepoOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := client.Issues.ListByRepo(ctx, owner, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			titles[issue.GetTitle()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return titles, nil
+}
+
+// newGitHubRESTClient builds a plain REST client for the stale-check and
+// issue-filing pass, which need REST-only fields (Archived, PushedAt,
+// Homepage) that the Source interface doesn't expose.
+func newGitHubRESTClient(ctx context.Context) *github.Client {
+	return newGitHubSource(ctx).client
+}